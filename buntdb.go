@@ -2,7 +2,6 @@ package buntdb
 
 import (
 	"context"
-	"encoding/json"
 	"sync"
 	"time"
 
@@ -11,10 +10,8 @@ import (
 )
 
 var (
-	_             session.ManagerStore = &managerStore{}
-	_             session.Store        = &store{}
-	jsonMarshal                        = json.Marshal
-	jsonUnmarshal                      = json.Unmarshal
+	_ session.ManagerStore = &managerStore{}
+	_ session.Store        = &store{}
 )
 
 // NewMemoryStore Create an instance of a memory store
@@ -37,15 +34,32 @@ func NewFileStore(path string) session.ManagerStore {
 
 func newManagerStore(db *buntdb.DB) *managerStore {
 	return &managerStore{
-		db: db,
+		db:    db,
+		codec: jsonCodec{},
 	}
 }
 
 type managerStore struct {
-	db *buntdb.DB
+	db          *buntdb.DB
+	codec       Codec
+	cipher      *keyringCipher
+	indexFields []string
+
+	pruneInterval time.Duration
+	onExpire      func(sid string)
+	onPrune       func(PruneStats)
+	shutdownOnce  sync.Once
+
+	writeCoalesceWindow time.Duration
+	coalesceMu          sync.Mutex
+	coalesced           map[string]*pendingWrite
 }
 
 func (s *managerStore) getValue(sid string) (string, error) {
+	if value, ok := s.pendingValue(sid); ok {
+		return value, nil
+	}
+
 	var value string
 
 	err := s.db.View(func(tx *buntdb.Tx) error {
@@ -67,7 +81,15 @@ func (s *managerStore) parseValue(value string) (map[string]interface{}, error)
 	var values map[string]interface{}
 
 	if len(value) > 0 {
-		err := jsonUnmarshal([]byte(value), &values)
+		if s.cipher != nil {
+			plain, err := s.cipher.decrypt(value)
+			if err != nil {
+				return nil, err
+			}
+			value = plain
+		}
+
+		err := s.codec.Unmarshal([]byte(value), &values)
 		if err != nil {
 			return nil, err
 		}
@@ -152,31 +174,52 @@ func (s *managerStore) Refresh(ctx context.Context, oldsid, sid string, expired
 	return newStore(ctx, s, sid, expired, values), nil
 }
 
+// Close stops the pruner and flushes any coalesced writes before
+// closing the underlying *buntdb.DB, same as Shutdown(context.Background()).
+// It predates the pruner and write-coalescing options and exists for
+// callers that don't need to pass a context; Shutdown is the lifecycle
+// method those options actually require.
 func (s *managerStore) Close() error {
-	return s.db.Close()
+	return s.Shutdown(context.Background())
 }
 
 func newStore(ctx context.Context, s *managerStore, sid string, expired int64, values map[string]interface{}) *store {
+	// A store created from a value that already exists in buntdb
+	// (Update/Refresh found one) is clean until something changes it;
+	// a brand new store (Create, or Update/Refresh finding nothing) has
+	// never been persisted, so its first Save must write regardless of
+	// whether Set was ever called.
+	persisted := values != nil
 	if values == nil {
 		values = make(map[string]interface{})
 	}
 
 	return &store{
-		db:      s.db,
-		ctx:     ctx,
-		sid:     sid,
-		expired: expired,
-		values:  values,
+		manager:   s,
+		codec:     s.codec,
+		cipher:    s.cipher,
+		ctx:       ctx,
+		sid:       sid,
+		expired:   expired,
+		values:    values,
+		persisted: persisted,
 	}
 }
 
 type store struct {
 	sync.RWMutex
-	ctx     context.Context
-	sid     string
-	expired int64
-	db      *buntdb.DB
-	values  map[string]interface{}
+	ctx       context.Context
+	sid       string
+	expired   int64
+	manager   *managerStore
+	codec     Codec
+	cipher    *keyringCipher
+	values    map[string]interface{}
+	dirtyKeys map[string]struct{}
+	// persisted is true once this session has been written to buntdb at
+	// least once; Save always writes while it's false, regardless of
+	// dirtyKeys, so a Create+Save with no Set still reaches the store.
+	persisted bool
 }
 
 func (s *store) Context() context.Context {
@@ -190,6 +233,7 @@ func (s *store) SessionID() string {
 func (s *store) Set(key string, value interface{}) {
 	s.Lock()
 	s.values[key] = value
+	s.markDirty(key)
 	s.Unlock()
 }
 
@@ -207,6 +251,7 @@ func (s *store) Delete(key string) interface{} {
 	if ok {
 		s.Lock()
 		delete(s.values, key)
+		s.markDirty(key)
 		s.Unlock()
 	}
 	return v
@@ -214,28 +259,90 @@ func (s *store) Delete(key string) interface{} {
 
 func (s *store) Flush() error {
 	s.Lock()
+	for key := range s.values {
+		s.markDirty(key)
+	}
 	s.values = make(map[string]interface{})
 	s.Unlock()
 	return s.Save()
 }
 
+// markDirty records that key changed since the store was loaded or last
+// saved. Callers must hold s's write lock. dirtyKeys is only ever
+// consulted for emptiness, but it is keyed by field name (rather than a
+// plain counter) so a future Save could persist a partial patch instead
+// of the whole value.
+func (s *store) markDirty(key string) {
+	if s.dirtyKeys == nil {
+		s.dirtyKeys = make(map[string]struct{})
+	}
+	s.dirtyKeys[key] = struct{}{}
+}
+
+// Save persists the session if it has never been persisted before, or
+// if a Set/Delete/Flush touched it since the last successful Save. This
+// avoids re-marshalling and rewriting the whole value through buntdb's
+// single writer for requests that only read the session.
+//
+// The snapshot of values is taken and dirtyKeys is cleared together
+// under a single write lock, before the write starts; a Set landing
+// after the snapshot was taken starts a fresh set of dirty keys, so it
+// isn't lost even though its value wasn't part of the write that just
+// committed — the next Save picks it up. If the write fails, the
+// snapshot's dirty keys are restored.
 func (s *store) Save() error {
-	var value string
+	s.Lock()
+	if s.persisted && len(s.dirtyKeys) == 0 {
+		s.Unlock()
+		return nil
+	}
 
-	s.RLock()
-	if len(s.values) > 0 {
-		buf, err := jsonMarshal(s.values)
+	values := make(map[string]interface{}, len(s.values))
+	for k, v := range s.values {
+		values[k] = v
+	}
+	saved := s.dirtyKeys
+	s.dirtyKeys = nil
+	s.Unlock()
+
+	// Always marshal, even for an empty map: getValue/Check/Update treat
+	// an empty stored string as "no session", so writing "" here for a
+	// Flush'd or never-Set session would make it indistinguishable from
+	// one that was never saved at all.
+	buf, err := s.codec.Marshal(values)
+	if err != nil {
+		s.restoreDirty(saved)
+		return err
+	}
+	value := string(buf)
+
+	if s.cipher != nil {
+		encrypted, err := s.cipher.encrypt(value)
 		if err != nil {
-			s.RUnlock()
+			s.restoreDirty(saved)
 			return err
 		}
-		value = string(buf)
+		value = encrypted
 	}
-	s.RUnlock()
 
-	return s.db.Update(func(tx *buntdb.Tx) error {
-		_, _, err := tx.Set(s.sid, value,
-			&buntdb.SetOptions{Expires: true, TTL: time.Duration(s.expired) * time.Second})
+	if err := s.manager.save(s.sid, value, s.expired); err != nil {
+		s.restoreDirty(saved)
 		return err
-	})
+	}
+
+	s.Lock()
+	s.persisted = true
+	s.Unlock()
+	return nil
+}
+
+// restoreDirty re-marks keys dirty after a failed Save, merging them
+// with any keys that became dirty while the failed write was in
+// flight.
+func (s *store) restoreDirty(keys map[string]struct{}) {
+	s.Lock()
+	for key := range keys {
+		s.markDirty(key)
+	}
+	s.Unlock()
 }
@@ -0,0 +1,109 @@
+package buntdb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tidwall/buntdb"
+)
+
+func TestWriteCoalescingEventuallyCommits(t *testing.T) {
+	ms := NewMemoryStoreWithOptions(WithWriteCoalescing(30 * time.Millisecond)).(*managerStore)
+	defer ms.Shutdown(context.Background())
+
+	st, err := ms.Create(context.Background(), "sess1", 3600)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	st.Set("a", "1")
+	if err := st.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		var value string
+		_ = ms.db.View(func(tx *buntdb.Tx) error {
+			v, err := tx.Get("sess1")
+			if err == nil {
+				value = v
+			}
+			return nil
+		})
+		if value != "" {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("expected coalesced write to eventually commit to the db")
+}
+
+func TestWriteCoalescingDoesNotDropEarlierWrite(t *testing.T) {
+	ms := NewMemoryStoreWithOptions(WithWriteCoalescing(200 * time.Millisecond))
+	defer ms.(*managerStore).Shutdown(context.Background())
+	ctx := context.Background()
+
+	st, err := ms.Create(ctx, "sess1", 3600)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	st.Set("a", "1")
+	if err := st.Save(); err != nil {
+		t.Fatalf("first Save: %v", err)
+	}
+
+	// A fresh request loading the session inside the coalescing window
+	// must see the pending write, not the stale on-disk value, or its
+	// own Save below would clobber "a" once the window flushes.
+	st2, err := ms.Update(ctx, "sess1", 3600)
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if v, ok := st2.Get("a"); !ok || v != "1" {
+		t.Fatalf("expected Update to see the coalesced write for a=1, got %v ok=%v", v, ok)
+	}
+
+	st2.Set("b", "2")
+	if err := st2.Save(); err != nil {
+		t.Fatalf("second Save: %v", err)
+	}
+
+	time.Sleep(400 * time.Millisecond)
+
+	final, err := ms.Update(ctx, "sess1", 3600)
+	if err != nil {
+		t.Fatalf("final Update: %v", err)
+	}
+	if v, ok := final.Get("a"); !ok || v != "1" {
+		t.Fatalf("expected a=1 to survive the coalescing window, got %v ok=%v", v, ok)
+	}
+	if v, ok := final.Get("b"); !ok || v != "2" {
+		t.Fatalf("expected b=2 to have been persisted, got %v ok=%v", v, ok)
+	}
+}
+
+func TestWriteCoalescingIndexSeesFlushedWrite(t *testing.T) {
+	ms := NewMemoryStoreWithOptions(WithWriteCoalescing(time.Hour), WithIndexes("user_id")).(*managerStore)
+	defer ms.Shutdown(context.Background())
+	ctx := context.Background()
+
+	st, err := ms.Create(ctx, "sess1", 3600)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	st.Set("user_id", "u1")
+	if err := st.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	// The coalescing window is an hour, so without a flush this would
+	// find nothing: buntdb's index only reflects what's been written.
+	stores, err := ms.ListByField(ctx, "user_id", "u1")
+	if err != nil {
+		t.Fatalf("ListByField: %v", err)
+	}
+	if len(stores) != 1 || stores[0].SessionID() != "sess1" {
+		t.Fatalf("expected ListByField to see the coalesced write after flushing, got %v", stores)
+	}
+}
@@ -0,0 +1,23 @@
+package buntdb
+
+import "encoding/json"
+
+// Codec marshals and unmarshals session values to and from the string
+// representation stored in buntdb. The store constructors use jsonCodec
+// by default; pass WithCodec to an *Options constructor to use a
+// different encoding, e.g. MessagePack via MsgpackCodec.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// jsonCodec is the default Codec, backed by encoding/json.
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
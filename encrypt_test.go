@@ -0,0 +1,95 @@
+package buntdb
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestEncryptedStoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions.db")
+	key := make([]byte, 32)
+	for i := range key {
+		key[i] = byte(i)
+	}
+
+	ms := NewEncryptedFileStore(path, key)
+	ctx := context.Background()
+
+	st, err := ms.Create(ctx, "sess1", 3600)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	st.Set("user_id", "u1")
+	if err := st.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if err := ms.(*managerStore).Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	reopened := NewEncryptedFileStore(path, key)
+	defer reopened.(*managerStore).Close()
+
+	st2, err := reopened.Update(ctx, "sess1", 3600)
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if v, ok := st2.Get("user_id"); !ok || v != "u1" {
+		t.Fatalf("expected decrypted user_id=u1, got %v ok=%v", v, ok)
+	}
+}
+
+func TestEncryptedStoreKeyRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions.db")
+	oldKey := make([]byte, 32)
+	for i := range oldKey {
+		oldKey[i] = byte(i)
+	}
+	newKey := make([]byte, 32)
+	for i := range newKey {
+		newKey[i] = byte(i + 1)
+	}
+
+	ms := NewFileStoreWithOptions(path, WithKeyring("old", map[string][]byte{"old": oldKey}))
+	ctx := context.Background()
+
+	st, err := ms.Create(ctx, "sess1", 3600)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	st.Set("user_id", "u1")
+	if err := st.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if err := ms.(*managerStore).Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// Rotate: the keyring now prefers newKey for writes but can still
+	// decrypt sessions written under oldKey.
+	rotated := NewFileStoreWithOptions(path,
+		WithKeyring("new", map[string][]byte{"old": oldKey, "new": newKey}))
+	defer rotated.(*managerStore).Close()
+
+	st2, err := rotated.Update(ctx, "sess1", 3600)
+	if err != nil {
+		t.Fatalf("Update after rotation: %v", err)
+	}
+	if v, ok := st2.Get("user_id"); !ok || v != "u1" {
+		t.Fatalf("expected session written under retired key to still decrypt, got %v ok=%v", v, ok)
+	}
+
+	st2.Set("user_id", "u2")
+	if err := st2.Save(); err != nil {
+		t.Fatalf("Save under rotated keyring: %v", err)
+	}
+
+	st3, err := rotated.Update(ctx, "sess1", 3600)
+	if err != nil {
+		t.Fatalf("Update after re-save: %v", err)
+	}
+	if v, ok := st3.Get("user_id"); !ok || v != "u2" {
+		t.Fatalf("expected re-saved value to round-trip under new primary key, got %v ok=%v", v, ok)
+	}
+}
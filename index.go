@@ -0,0 +1,160 @@
+package buntdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/go-session/session"
+	"github.com/tidwall/buntdb"
+)
+
+// indexPrefix namespaces buntdb indexes created for session fields so they
+// don't collide with indexes an application creates on the same database.
+const indexPrefix = "idx_"
+
+func indexName(field string) string {
+	return indexPrefix + field
+}
+
+// validateIndexCompatibility rejects combining WithIndexes with
+// anything that stops the stored value from being the raw JSON object
+// IndexJSON expects to parse: a non-JSON Codec (e.g. MsgpackCodec) or
+// encryption at rest (WithKeyring/NewEncryptedFileStore). Either one
+// makes buntdb's index silently see binary/ciphertext instead of JSON,
+// so every AscendEqual in ListByField/DeleteAllByField just matches
+// nothing rather than erroring.
+func validateIndexCompatibility(o *options) error {
+	if len(o.indexFields) == 0 {
+		return nil
+	}
+	if _, ok := o.codec.(jsonCodec); !ok {
+		return fmt.Errorf("buntdb: WithIndexes requires the default JSON codec, got %T; buntdb.IndexJSON parses the raw stored value, which a non-JSON codec would break", o.codec)
+	}
+	if o.keyring != nil {
+		return errors.New("buntdb: WithIndexes cannot be combined with WithKeyring/NewEncryptedFileStore; indexes scan the raw stored value, which encryption replaces with ciphertext")
+	}
+	return nil
+}
+
+// NewMemoryStoreWithIndexes creates an in-memory store and registers a
+// secondary index for each of the given top-level session fields (e.g.
+// "user_id", "ip", "role"). The indexes enable ListByField and
+// DeleteAllByField. It is a shorthand for
+// NewMemoryStoreWithOptions(WithIndexes(fields...)).
+func NewMemoryStoreWithIndexes(fields ...string) session.ManagerStore {
+	return NewMemoryStoreWithOptions(WithIndexes(fields...))
+}
+
+// NewFileStoreWithIndexes creates a file-backed store and registers a
+// secondary index for each of the given top-level session fields (e.g.
+// "user_id", "ip", "role"). The indexes enable ListByField and
+// DeleteAllByField, and are rebuilt automatically by buntdb from the
+// persisted keys whenever the store is reopened. It is a shorthand for
+// NewFileStoreWithOptions(path, WithIndexes(fields...)).
+func NewFileStoreWithIndexes(path string, fields ...string) session.ManagerStore {
+	return NewFileStoreWithOptions(path, WithIndexes(fields...))
+}
+
+// buildPivot builds the single-field JSON document buntdb needs as the
+// pivot for AscendEqual: every key whose IndexJSON(field) value compares
+// equal to the pivot's is returned.
+func buildPivot(field string, value interface{}) (string, error) {
+	buf, err := json.Marshal(map[string]interface{}{field: value})
+	if err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+// ListByField returns every session whose top-level JSON field equals
+// value, using the index registered for field. It returns
+// session.ErrNotFound-free results; an empty slice is returned if no
+// session matches.
+func (s *managerStore) ListByField(ctx context.Context, field string, value interface{}) ([]session.Store, error) {
+	// buntdb's index only sees what's actually been written; flush any
+	// writes still sitting in the coalescing buffer first so this scan
+	// doesn't miss a session that was just Saved.
+	s.flushCoalesced()
+
+	idx := indexName(field)
+	pivot, err := buildPivot(field, value)
+	if err != nil {
+		return nil, err
+	}
+
+	var stores []session.Store
+	err = s.db.View(func(tx *buntdb.Tx) error {
+		var iterErr error
+		tx.AscendEqual(idx, pivot, func(key, val string) bool {
+			values, err := s.parseValue(val)
+			if err != nil {
+				iterErr = err
+				return false
+			}
+
+			ttl, err := tx.TTL(key)
+			if err != nil {
+				iterErr = err
+				return false
+			}
+
+			stores = append(stores, newStore(ctx, s, key, int64(ttl.Seconds()), values))
+			return true
+		})
+		return iterErr
+	})
+	if err != nil {
+		return nil, fmt.Errorf("buntdb: list by field %q: %w", field, err)
+	}
+
+	return stores, nil
+}
+
+// DeleteAllByField deletes every session whose top-level JSON field
+// equals value, using the index registered for field, and returns the
+// number of sessions removed. This is the bulk operation operators use
+// to forcibly terminate every session belonging to a deleted or banned
+// user.
+func (s *managerStore) DeleteAllByField(ctx context.Context, field string, value interface{}) (int, error) {
+	// Same reasoning as ListByField: the index can't see a session
+	// still sitting in the coalescing buffer.
+	s.flushCoalesced()
+
+	idx := indexName(field)
+	pivot, err := buildPivot(field, value)
+	if err != nil {
+		return 0, err
+	}
+
+	var keys []string
+	err = s.db.View(func(tx *buntdb.Tx) error {
+		tx.AscendEqual(idx, pivot, func(key, _ string) bool {
+			keys = append(keys, key)
+			return true
+		})
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("buntdb: delete all by field %q: %w", field, err)
+	}
+
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	err = s.db.Update(func(tx *buntdb.Tx) error {
+		for _, key := range keys {
+			if _, err := tx.Delete(key); err != nil && err != buntdb.ErrNotFound {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("buntdb: delete all by field %q: %w", field, err)
+	}
+
+	return len(keys), nil
+}
@@ -0,0 +1,66 @@
+package buntdb
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGuardRejectsIndexesWithNonJSONCodec(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic combining WithIndexes with a non-JSON codec")
+		}
+	}()
+	NewMemoryStoreWithOptions(WithCodec(MsgpackCodec{}), WithIndexes("user_id"))
+}
+
+func TestGuardRejectsIndexesWithEncryption(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic combining WithIndexes with encryption at rest")
+		}
+	}()
+	key := make([]byte, 32)
+	NewEncryptedFileStore(filepath.Join(t.TempDir(), "sessions.db"), key, WithIndexes("user_id"))
+}
+
+func TestGuardAllowsIndexesWithDefaultCodec(t *testing.T) {
+	ms := NewMemoryStoreWithOptions(WithIndexes("user_id"))
+	defer ms.(*managerStore).Close()
+}
+
+func TestIndexSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions.db")
+
+	ms := NewFileStoreWithIndexes(path, "user_id")
+	ctx := context.Background()
+
+	st, err := ms.Create(ctx, "sess1", 3600)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	st.Set("user_id", "u1")
+	if err := st.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if err := ms.(*managerStore).Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("expected %s to exist after Close: %v", path, err)
+	}
+
+	reopened := NewFileStoreWithIndexes(path, "user_id")
+	defer reopened.(*managerStore).Close()
+
+	stores, err := reopened.(*managerStore).ListByField(ctx, "user_id", "u1")
+	if err != nil {
+		t.Fatalf("ListByField after restart: %v", err)
+	}
+	if len(stores) != 1 || stores[0].SessionID() != "sess1" {
+		t.Fatalf("expected index rebuilt from disk to find sess1, got %v", stores)
+	}
+}
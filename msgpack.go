@@ -0,0 +1,17 @@
+package buntdb
+
+import "github.com/vmihailenco/msgpack/v5"
+
+// MsgpackCodec encodes session values with MessagePack instead of JSON.
+// It is meaningfully smaller and faster for session blobs with typed
+// values, and unlike encoding/json it round-trips integers without
+// coercing them to float64. Use it via WithCodec(MsgpackCodec{}).
+type MsgpackCodec struct{}
+
+func (MsgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+func (MsgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	return msgpack.Unmarshal(data, v)
+}
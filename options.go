@@ -0,0 +1,104 @@
+package buntdb
+
+import (
+	"time"
+
+	"github.com/go-session/session"
+	"github.com/tidwall/buntdb"
+)
+
+// Option configures a store created via NewFileStoreWithOptions or
+// NewMemoryStoreWithOptions.
+type Option func(*options)
+
+type options struct {
+	codec       Codec
+	indexFields []string
+
+	pruneInterval time.Duration
+	onExpire      func(sid string)
+	onPrune       func(PruneStats)
+
+	keyring *keyring
+
+	writeCoalesceWindow time.Duration
+}
+
+// WithCodec sets the Codec used to marshal and unmarshal session values.
+// The default is JSON; pass MsgpackCodec{} to switch to MessagePack.
+func WithCodec(codec Codec) Option {
+	return func(o *options) {
+		o.codec = codec
+	}
+}
+
+// WithIndexes registers a secondary index for each of the given
+// top-level session fields (e.g. "user_id", "ip", "role"), enabling
+// ListByField and DeleteAllByField.
+func WithIndexes(fields ...string) Option {
+	return func(o *options) {
+		o.indexFields = append(o.indexFields, fields...)
+	}
+}
+
+// NewMemoryStoreWithOptions creates an in-memory store configured by
+// opts. See WithCodec and WithIndexes.
+func NewMemoryStoreWithOptions(opts ...Option) session.ManagerStore {
+	db, err := buntdb.Open(":memory:")
+	if err != nil {
+		panic(err)
+	}
+	return newManagerStoreWithOptions(db, opts)
+}
+
+// NewFileStoreWithOptions creates a file-backed store configured by
+// opts. See WithCodec and WithIndexes.
+func NewFileStoreWithOptions(path string, opts ...Option) session.ManagerStore {
+	db, err := buntdb.Open(path)
+	if err != nil {
+		panic(err)
+	}
+	return newManagerStoreWithOptions(db, opts)
+}
+
+func newManagerStoreWithOptions(db *buntdb.DB, opts []Option) *managerStore {
+	o := &options{codec: jsonCodec{}}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if err := validateIndexCompatibility(o); err != nil {
+		panic(err)
+	}
+
+	var cipher *keyringCipher
+	if o.keyring != nil {
+		c, err := newKeyringCipher(o.keyring.primaryID, o.keyring.keys)
+		if err != nil {
+			panic(err)
+		}
+		cipher = c
+	}
+
+	s := &managerStore{
+		db:                  db,
+		codec:               o.codec,
+		cipher:              cipher,
+		indexFields:         o.indexFields,
+		pruneInterval:       o.pruneInterval,
+		onExpire:            o.onExpire,
+		onPrune:             o.onPrune,
+		writeCoalesceWindow: o.writeCoalesceWindow,
+	}
+	for _, field := range o.indexFields {
+		if err := db.CreateIndex(indexName(field), "*", buntdb.IndexJSON(field)); err != nil {
+			panic(err)
+		}
+	}
+
+	if s.pruneInterval > 0 {
+		s.startPruner()
+	}
+
+	return s
+}
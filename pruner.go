@@ -0,0 +1,116 @@
+package buntdb
+
+import (
+	"context"
+	"time"
+
+	"github.com/tidwall/buntdb"
+)
+
+// PruneStats is reported to the callback registered with WithOnPrune
+// once per batch of sessions buntdb reaps.
+type PruneStats struct {
+	// Pruned is the number of expired sessions removed.
+	Pruned int
+	// Scanned is the number of keys buntdb identified as expired in
+	// this batch; always equal to Pruned, since buntdb only ever
+	// reports keys it has already decided are expired.
+	Scanned int
+	// Duration is how long the batch took to delete.
+	Duration time.Duration
+}
+
+// WithPruneInterval enables the pruner subsystem, registering with
+// buntdb's own backgroundManager (via Config.OnExpired) so WithOnExpire
+// and WithOnPrune fire as sessions expire. d is accepted for backwards
+// compatibility with the option's signature, but no longer drives an
+// independent sweep: buntdb only ever notices expired keys on its own
+// backgroundManager cycle, which runs once a second regardless of d.
+// Pass any value greater than zero to turn the feature on.
+func WithPruneInterval(d time.Duration) Option {
+	return func(o *options) {
+		o.pruneInterval = d
+	}
+}
+
+// WithOnExpire registers a callback fired once per session the pruner
+// removes, so applications can react to expirations, e.g. revoking
+// related refresh tokens.
+func WithOnExpire(fn func(sid string)) Option {
+	return func(o *options) {
+		o.onExpire = fn
+	}
+}
+
+// WithOnPrune registers a callback fired once per batch of expired
+// sessions the pruner removes, with the batch's metrics. It is not
+// called for a cycle in which nothing expired.
+func WithOnPrune(fn func(PruneStats)) Option {
+	return func(o *options) {
+		o.onPrune = fn
+	}
+}
+
+func (s *managerStore) startPruner() {
+	// Registering Config.OnExpired changes buntdb's own behavior:
+	// backgroundManager only deletes expired keys itself when
+	// OnExpired and OnExpiredSync are both nil. Once OnExpired is set,
+	// buntdb hands it the expired keys and deletes nothing — "the
+	// deletion of the timed-out item is the explicit responsibility of
+	// this callback" (per buntdb's doc comment on OnExpiredSync, which
+	// applies equally here). onExpired must therefore delete the keys
+	// itself, or they'd become invisible to reads but never actually
+	// leave the keys btree.
+	_ = s.db.SetConfig(buntdb.Config{OnExpired: s.onExpired})
+}
+
+// onExpired is registered with buntdb as Config.OnExpired. buntdb calls
+// it once per backgroundManager cycle (roughly once a second) with
+// every key it has identified as expired, and, because OnExpired is
+// set, does not delete them itself — see startPruner.
+func (s *managerStore) onExpired(keys []string) {
+	start := time.Now()
+
+	_ = s.db.Update(func(tx *buntdb.Tx) error {
+		for _, key := range keys {
+			if _, err := tx.Delete(key); err != nil && err != buntdb.ErrNotFound {
+				return err
+			}
+		}
+		return nil
+	})
+	elapsed := time.Since(start)
+
+	if s.onExpire != nil {
+		for _, key := range keys {
+			s.onExpire(key)
+		}
+	}
+	if s.onPrune != nil {
+		s.onPrune(PruneStats{Pruned: len(keys), Scanned: len(keys), Duration: elapsed})
+	}
+}
+
+// Shutdown flushes any pending writes and closes the underlying
+// *buntdb.DB. It is safe to call once; subsequent calls are no-ops.
+// Callers that don't need context-aware cancellation can keep using
+// Close.
+func (s *managerStore) Shutdown(ctx context.Context) error {
+	var err error
+	s.shutdownOnce.Do(func() {
+		s.flushCoalesced()
+
+		done := make(chan struct{})
+		go func() {
+			err = s.db.Close()
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	})
+	return err
+}
@@ -0,0 +1,69 @@
+package buntdb
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tidwall/buntdb"
+)
+
+func TestPrunerFiresOnExpire(t *testing.T) {
+	var mu sync.Mutex
+	var expired []string
+
+	ms := NewMemoryStoreWithOptions(
+		WithPruneInterval(20*time.Millisecond),
+		WithOnExpire(func(sid string) {
+			mu.Lock()
+			expired = append(expired, sid)
+			mu.Unlock()
+		}),
+	)
+	defer ms.(*managerStore).Shutdown(context.Background())
+
+	st, err := ms.Create(context.Background(), "sess1", 1)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	st.Set("user_id", "u1")
+	if err := st.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	ok, err := ms.Check(context.Background(), "sess1")
+	if err != nil || !ok {
+		t.Fatalf("expected session present right after Save, got ok=%v err=%v", ok, err)
+	}
+
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		mu.Lock()
+		n := len(expired)
+		mu.Unlock()
+		if n > 0 {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	mu.Lock()
+	if len(expired) != 1 || expired[0] != "sess1" {
+		mu.Unlock()
+		t.Fatalf("expected OnExpire to fire once for sess1, got %v", expired)
+	}
+	mu.Unlock()
+
+	// The callback firing isn't enough on its own: registering
+	// Config.OnExpired stops buntdb from deleting expired keys itself,
+	// so onExpired must do it. Confirm the key is actually gone from
+	// the db, not just invisible to TTL-respecting reads.
+	err = ms.(*managerStore).db.View(func(tx *buntdb.Tx) error {
+		_, err := tx.Get("sess1", true)
+		return err
+	})
+	if err != buntdb.ErrNotFound {
+		t.Fatalf("expected sess1 to be deleted from the db, got err=%v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package buntdb
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestSaveWithoutSetStillPersists(t *testing.T) {
+	ms := NewMemoryStore()
+	defer ms.Close()
+
+	st, err := ms.Create(context.Background(), "sess1", 3600)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := st.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	ok, err := ms.Check(context.Background(), "sess1")
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if !ok {
+		t.Fatal("expected session to exist after Create+Save with no Set, but it was absent")
+	}
+}
+
+func TestSaveSkipsRewriteWhenClean(t *testing.T) {
+	ms := NewMemoryStore()
+	defer ms.Close()
+
+	st, err := ms.Create(context.Background(), "sess1", 3600)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	st.Set("a", "1")
+	if err := st.Save(); err != nil {
+		t.Fatalf("first Save: %v", err)
+	}
+
+	// Reload and Save again without touching anything: must not wipe
+	// out the previously persisted value.
+	st2, err := ms.Update(context.Background(), "sess1", 3600)
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if err := st2.Save(); err != nil {
+		t.Fatalf("second Save: %v", err)
+	}
+
+	reloaded, err := ms.Update(context.Background(), "sess1", 3600)
+	if err != nil {
+		t.Fatalf("reload Update: %v", err)
+	}
+	v, ok := reloaded.Get("a")
+	if !ok || v != "1" {
+		t.Fatalf("expected a=1 to survive an untouched Save, got %v ok=%v", v, ok)
+	}
+}
+
+// blockingCodec wraps jsonCodec but blocks inside Marshal until
+// released, so a test can land a Set in the window between Save
+// snapshotting+clearing dirtyKeys and the write actually committing.
+type blockingCodec struct {
+	release chan struct{}
+}
+
+func (c *blockingCodec) Marshal(v interface{}) ([]byte, error) {
+	<-c.release
+	return json.Marshal(v)
+}
+
+func (c *blockingCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func TestSaveDoesNotDropConcurrentSet(t *testing.T) {
+	ms := NewMemoryStoreWithOptions(WithCodec(&blockingCodec{release: make(chan struct{})})).(*managerStore)
+	defer ms.Close()
+
+	st := newStore(context.Background(), ms, "sess1", 3600, nil)
+	st.Set("a", "1")
+
+	done := make(chan error, 1)
+	go func() { done <- st.Save() }()
+
+	// Give Save a chance to snapshot "a" and clear dirtyKeys before it
+	// blocks inside Marshal.
+	time.Sleep(50 * time.Millisecond)
+	st.Set("b", "2")
+
+	close(st.codec.(*blockingCodec).release)
+	if err := <-done; err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	st.RLock()
+	_, stillDirty := st.dirtyKeys["b"]
+	st.RUnlock()
+	if !stillDirty {
+		t.Fatal("Set landing during Save's marshal/commit must stay dirty, not be silently cleared")
+	}
+
+	if err := st.Save(); err != nil {
+		t.Fatalf("second Save: %v", err)
+	}
+
+	reloaded, err := ms.Update(context.Background(), "sess1", 3600)
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if v, ok := reloaded.Get("b"); !ok || v != "2" {
+		t.Fatalf("expected b=2 to have been persisted by the follow-up Save, got %v ok=%v", v, ok)
+	}
+}
@@ -0,0 +1,98 @@
+package buntdb
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// ErrKeyNotFound is returned by GetJSON when key is not present in the
+// session.
+var ErrKeyNotFound = errors.New("buntdb: key not found")
+
+// GetString returns the string stored at key, or ("", false) if key is
+// absent or holds a value that is not a string.
+func (s *store) GetString(key string) (string, bool) {
+	s.RLock()
+	defer s.RUnlock()
+
+	v, ok := s.values[key]
+	if !ok {
+		return "", false
+	}
+	str, ok := v.(string)
+	return str, ok
+}
+
+// GetInt64 returns the integer stored at key, or (0, false) if key is
+// absent or holds a value that isn't an integer or a float64. It accepts every signed
+// and unsigned integer type, which MsgpackCodec produces at the smallest
+// size that fits, as well as float64, which is what encoding/json
+// produces for numbers decoded into interface{}.
+func (s *store) GetInt64(key string) (int64, bool) {
+	s.RLock()
+	defer s.RUnlock()
+
+	v, ok := s.values[key]
+	if !ok {
+		return 0, false
+	}
+
+	switch n := v.(type) {
+	case int64:
+		return n, true
+	case int:
+		return int64(n), true
+	case int8:
+		return int64(n), true
+	case int16:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case uint8:
+		return int64(n), true
+	case uint16:
+		return int64(n), true
+	case uint32:
+		return int64(n), true
+	case uint64:
+		return int64(n), true
+	case float64:
+		return int64(n), true
+	default:
+		return 0, false
+	}
+}
+
+// GetBool returns the boolean stored at key, or (false, false) if key
+// is absent or holds a value that is not a bool.
+func (s *store) GetBool(key string) (bool, bool) {
+	s.RLock()
+	defer s.RUnlock()
+
+	v, ok := s.values[key]
+	if !ok {
+		return false, false
+	}
+	b, ok := v.(bool)
+	return b, ok
+}
+
+// GetJSON unmarshals the value stored at key into out, which must be a
+// pointer. It returns ErrKeyNotFound if key is absent. Unlike Get,
+// GetJSON always round-trips through encoding/json regardless of the
+// store's configured Codec, so out can be any concrete type rather than
+// the map[string]interface{} shape values decode to by default.
+func (s *store) GetJSON(key string, out interface{}) error {
+	s.RLock()
+	v, ok := s.values[key]
+	s.RUnlock()
+	if !ok {
+		return ErrKeyNotFound
+	}
+
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(buf, out)
+}